@@ -0,0 +1,322 @@
+// author: Gary A. Stafford
+// purpose: Composed authentication for the API: the original static
+//          X-API-Key header, or a JWT bearer token carrying role claims.
+//          Also issues JWTs from a small env-based user store via /login.
+// modified: 2021-07-02
+
+package main
+
+import (
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	userContextKey  = "user"
+	rolesContextKey = "roles"
+
+	roleWriter = "writer"
+	roleReader = "reader"
+)
+
+var (
+	jwtSecret  = getEnv("JWT_SECRET", "")
+	jwtJWKSURL = getEnv("JWT_JWKS_URL", "")
+
+	// authUsers is the env-based user store for /login, in the form
+	// "user:password:role,user2:password2:role2". Intended for initial
+	// rollout; swap for a real identity provider once JWT_JWKS_URL is set.
+	authUsers = getEnv("AUTH_USERS", "")
+)
+
+// authMiddleware accepts either the static X-API-Key header or a JWT
+// bearer token. A valid API key is granted every role; a JWT's roles come
+// from its "roles" claim. Requests with neither are rejected.
+func authMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if key := c.Request().Header.Get("X-API-Key"); key != "" {
+			log.Debugf("API_KEY: %v", apiKey)
+			if key != apiKey {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid API key")
+			}
+			c.Set(userContextKey, "api-key")
+			c.Set(rolesContextKey, []string{roleWriter, roleReader})
+			return next(c)
+		}
+
+		auth := c.Request().Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") {
+			return echo.NewHTTPError(http.StatusUnauthorized, "missing X-API-Key or bearer token")
+		}
+
+		claims, err := parseBearerToken(strings.TrimPrefix(auth, "Bearer "))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+		}
+
+		c.Set(userContextKey, claims.Subject)
+		c.Set(rolesContextKey, claims.Roles)
+		return next(c)
+	}
+}
+
+// requireRole rejects the request unless the authenticated principal was
+// granted role. Must run after authMiddleware.
+func requireRole(role string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			roles, _ := c.Get(rolesContextKey).([]string)
+			for _, r := range roles {
+				if r == role {
+					return next(c)
+				}
+			}
+			return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("requires %s role", role))
+		}
+	}
+}
+
+// rateLimitBySubject keys the rate limiter on the authenticated subject
+// (API key principal or JWT sub claim) rather than remote IP, so a shared
+// ingress doesn't throttle every caller behind it together.
+func rateLimitBySubject() echo.MiddlewareFunc {
+	return middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+		Skipper: middleware.DefaultSkipper,
+		Store: middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+			Rate:  20,
+			Burst: 20,
+		}),
+		IdentifierExtractor: func(c echo.Context) (string, error) {
+			if subject, ok := c.Get(userContextKey).(string); ok && subject != "" {
+				return subject, nil
+			}
+			return c.RealIP(), nil
+		},
+	})
+}
+
+// jwtClaims carries the subject and roles embedded in issued tokens.
+type jwtClaims struct {
+	Roles []string `json:"roles"`
+	jwt.RegisteredClaims
+}
+
+// parseBearerToken verifies tokenString against JWT_SECRET (HS256) or the
+// JWKS at JWT_JWKS_URL (RS256), whichever is configured.
+func parseBearerToken(tokenString string) (*jwtClaims, error) {
+	claims := &jwtClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			if jwtSecret == "" {
+				return nil, fmt.Errorf("JWT_SECRET not configured")
+			}
+			return []byte(jwtSecret), nil
+		case *jwt.SigningMethodRSA:
+			return fetchJWKSPublicKey(token)
+		default:
+			return nil, fmt.Errorf("unsupported signing method %v", token.Header["alg"])
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	return claims, nil
+}
+
+var jwksCacheTTL = getEnvDuration("JWT_JWKS_CACHE_TTL", 15*time.Minute)
+
+// jwksCache holds the most recent fetch of JWT_JWKS_URL, keyed by "kid" so
+// parseBearerToken can find the right key during a provider's key rotation.
+// It is refreshed lazily, at most once per jwksCacheTTL.
+var jwksCache = struct {
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}{}
+
+// jwkSet is the response body of a standard JWKS endpoint.
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// jwk is the subset of RFC 7517 fields needed to reconstruct an RSA
+// public key: modulus (n) and exponent (e), both base64url-encoded.
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// fetchJWKSPublicKey resolves the RSA public key for token from the JWKS
+// document at JWT_JWKS_URL, matching on the token's "kid" header. The JWKS
+// is cached for jwksCacheTTL and refetched on a cache miss, so a kid
+// introduced by the provider's key rotation is picked up without a restart.
+func fetchJWKSPublicKey(token *jwt.Token) (*rsa.PublicKey, error) {
+	if jwtJWKSURL == "" {
+		return nil, fmt.Errorf("JWT_JWKS_URL not configured")
+	}
+
+	kid, ok := token.Header["kid"].(string)
+	if !ok || kid == "" {
+		return nil, fmt.Errorf("token missing kid header")
+	}
+
+	if key := lookupJWKSKey(kid, false); key != nil {
+		return key, nil
+	}
+	if key := lookupJWKSKey(kid, true); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no JWKS key found for kid %q", kid)
+}
+
+// lookupJWKSKey returns the cached key for kid, refreshing the cache first
+// if forceRefresh is set or the cache is older than jwksCacheTTL.
+func lookupJWKSKey(kid string, forceRefresh bool) *rsa.PublicKey {
+	jwksCache.mu.Lock()
+	defer jwksCache.mu.Unlock()
+
+	if forceRefresh || time.Since(jwksCache.fetchedAt) > jwksCacheTTL {
+		keys, err := fetchJWKS(jwtJWKSURL)
+		if err != nil {
+			log.Errorf("fetchJWKS Error: %v", err)
+		} else {
+			jwksCache.keys = keys
+			jwksCache.fetchedAt = time.Now()
+		}
+	}
+
+	return jwksCache.keys[kid]
+}
+
+// fetchJWKS downloads and parses the JWKS document at url, returning its
+// RSA keys indexed by kid. Non-RSA entries are skipped.
+func fetchJWKS(url string) (map[string]*rsa.PublicKey, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d fetching JWKS", resp.StatusCode)
+	}
+
+	var set jwkSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || k.Kid == "" {
+			continue
+		}
+		pubKey, err := k.rsaPublicKey()
+		if err != nil {
+			log.Errorf("jwk.rsaPublicKey Error: %v", err)
+			continue
+		}
+		keys[k.Kid] = pubKey
+	}
+	return keys, nil
+}
+
+// rsaPublicKey decodes k's base64url modulus and exponent into an
+// *rsa.PublicKey.
+func (k jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("decoding n: %w", err)
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("decoding e: %w", err)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(new(big.Int).SetBytes(eBytes).Int64()),
+	}, nil
+}
+
+// loginRequest is the payload for POST /login.
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type loginResponse struct {
+	Token string `json:"token"`
+}
+
+// postLogin authenticates against the env-based AUTH_USERS store and
+// issues a signed JWT carrying the user's roles.
+func postLogin(c echo.Context) error {
+	if jwtSecret == "" {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "JWT_SECRET not configured")
+	}
+
+	var req loginRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	roles, ok := authenticate(req.Username, req.Password)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
+	}
+
+	claims := &jwtClaims{
+		Roles: roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   req.Username,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(jwtSecret))
+	if err != nil {
+		log.Errorf("token.SignedString Error: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, loginResponse{Token: signed})
+}
+
+// authenticate checks username/password against AUTH_USERS, an env var of
+// the form "user:password:role,user2:password2:role2".
+func authenticate(username, password string) ([]string, bool) {
+	for _, entry := range strings.Split(authUsers, ",") {
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == username && subtle.ConstantTimeCompare([]byte(parts[1]), []byte(password)) == 1 {
+			return strings.Split(parts[2], "|"), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func withAuthUsers(t *testing.T, value string) {
+	t.Helper()
+	original := authUsers
+	authUsers = value
+	t.Cleanup(func() { authUsers = original })
+}
+
+func TestAuthenticate(t *testing.T) {
+	withAuthUsers(t, "alice:s3cret:reader,bob:hunter2:writer|reader")
+
+	tests := []struct {
+		name      string
+		username  string
+		password  string
+		wantOK    bool
+		wantRoles []string
+	}{
+		{"valid single role", "alice", "s3cret", true, []string{"reader"}},
+		{"valid multiple roles", "bob", "hunter2", true, []string{"writer", "reader"}},
+		{"wrong password", "alice", "wrong", false, nil},
+		{"unknown user", "carol", "anything", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roles, ok := authenticate(tt.username, tt.password)
+			if ok != tt.wantOK {
+				t.Fatalf("authenticate() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !tt.wantOK {
+				return
+			}
+			if len(roles) != len(tt.wantRoles) {
+				t.Fatalf("authenticate() roles = %v, want %v", roles, tt.wantRoles)
+			}
+			for i := range roles {
+				if roles[i] != tt.wantRoles[i] {
+					t.Errorf("authenticate() roles[%d] = %q, want %q", i, roles[i], tt.wantRoles[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseBearerTokenHS256(t *testing.T) {
+	original := jwtSecret
+	jwtSecret = "test-secret"
+	t.Cleanup(func() { jwtSecret = original })
+
+	claims := &jwtClaims{
+		Roles: []string{roleReader},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "alice",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(jwtSecret))
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	got, err := parseBearerToken(signed)
+	if err != nil {
+		t.Fatalf("parseBearerToken() error = %v", err)
+	}
+	if got.Subject != "alice" || len(got.Roles) != 1 || got.Roles[0] != roleReader {
+		t.Errorf("parseBearerToken() claims = %+v, want subject alice, roles [reader]", got)
+	}
+}
+
+func TestParseBearerTokenHS256WrongSecret(t *testing.T) {
+	original := jwtSecret
+	jwtSecret = "right-secret"
+	t.Cleanup(func() { jwtSecret = original })
+
+	claims := &jwtClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: "alice"}}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("wrong-secret"))
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	if _, err := parseBearerToken(signed); err == nil {
+		t.Fatal("parseBearerToken() error = nil, want error for token signed with the wrong secret")
+	}
+}
+
+// rsaJWKS serves a single RSA key as a JWKS document, for RS256 tests.
+func rsaJWKS(t *testing.T, kid string, key *rsa.PrivateKey) *httptest.Server {
+	t.Helper()
+
+	n := base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes())
+	e := base64.RawURLEncoding.EncodeToString([]byte{1, 0, 1}) // 65537
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{"kid": kid, "kty": "RSA", "n": n, "e": e}},
+		})
+	}))
+}
+
+func TestParseBearerTokenRS256ViaJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+
+	server := rsaJWKS(t, "test-kid", key)
+	t.Cleanup(server.Close)
+
+	originalURL := jwtJWKSURL
+	jwtJWKSURL = server.URL
+	t.Cleanup(func() { jwtJWKSURL = originalURL })
+
+	originalKeys, originalFetchedAt := jwksCache.keys, jwksCache.fetchedAt
+	jwksCache.keys = nil
+	jwksCache.fetchedAt = time.Time{}
+	t.Cleanup(func() {
+		jwksCache.keys, jwksCache.fetchedAt = originalKeys, originalFetchedAt
+	})
+
+	claims := &jwtClaims{
+		Roles:            []string{roleWriter},
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "bob", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-kid"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	got, err := parseBearerToken(signed)
+	if err != nil {
+		t.Fatalf("parseBearerToken() error = %v", err)
+	}
+	if got.Subject != "bob" || len(got.Roles) != 1 || got.Roles[0] != roleWriter {
+		t.Errorf("parseBearerToken() claims = %+v, want subject bob, roles [writer]", got)
+	}
+}
+
+func TestParseBearerTokenRS256UnknownKid(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+
+	server := rsaJWKS(t, "known-kid", key)
+	t.Cleanup(server.Close)
+
+	originalURL := jwtJWKSURL
+	jwtJWKSURL = server.URL
+	t.Cleanup(func() { jwtJWKSURL = originalURL })
+
+	originalKeys, originalFetchedAt := jwksCache.keys, jwksCache.fetchedAt
+	jwksCache.keys = nil
+	jwksCache.fetchedAt = time.Time{}
+	t.Cleanup(func() {
+		jwksCache.keys, jwksCache.fetchedAt = originalKeys, originalFetchedAt
+	})
+
+	claims := &jwtClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: "eve"}}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "unknown-kid"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	if _, err := parseBearerToken(signed); err == nil {
+		t.Fatal("parseBearerToken() error = nil, want error for a kid absent from the JWKS")
+	}
+}
@@ -0,0 +1,432 @@
+// author: Gary A. Stafford
+// purpose: Batch write support for NLPText records, with an optional
+//          SQS-backed async ingestion path for high-throughput callers.
+// modified: 2021-06-20
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	dynamoBatchLimit = 25 // BatchWriteItem hard limit per call
+	backoffBase      = 100 * time.Millisecond
+	backoffCap       = 30 * time.Second
+)
+
+var (
+	asyncMode        = getEnv("ASYNC_MODE", "false") == "true"
+	workerCount      = getEnvInt("WORKER_COUNT", 4)
+	batchMaxAttempts = getEnvInt("BATCH_MAX_ATTEMPTS", 8)
+	sqsQueueURL      = getEnv("SQS_QUEUE_URL", "")
+	sqsDLQURL        = getEnv("SQS_DLQ_URL", "")
+
+	queueDepth   int64 // items currently in flight (enqueued, not yet durably written)
+	queueRetries int64 // cumulative retry count across all batch writes
+	dlqSize      int64 // items permanently failed and sent to the DLQ
+)
+
+func getEnvInt(key string, fallback int) int {
+	if value, ok := os.LookupEnv(key); ok {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+// BatchRecordRequest is the payload accepted by POST /records/batch: a flat
+// array of text items, mirroring the single-item shape of /record.
+type BatchRecordRequest struct {
+	Items []string `json:"items"`
+}
+
+// BatchItemResult reports the outcome of a single item in a batch write.
+// Item ordering in the response matches the request, but DynamoDB offers
+// no ordering guarantee for when each item is actually persisted.
+type BatchItemResult struct {
+	Hash   string `json:"hash"`
+	Status string `json:"status"` // "written", "queued", or "failed"
+	Error  string `json:"error,omitempty"`
+}
+
+type BatchRecordResponse struct {
+	Results []BatchItemResult `json:"results"`
+}
+
+func postBatchRecords(c echo.Context) error {
+	var req BatchRecordRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		log.Errorf("json.NewDecoder Error: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	items := make([]NlpText, 0, len(req.Items))
+	for _, text := range req.Items {
+		if len(text) > 1000 {
+			text = text[0:1000] + "..."
+		}
+		items = append(items, NlpText{
+			Hash:      getMD5Hash(text),
+			Text:      text,
+			Timestamp: time.Now().Unix(),
+		})
+	}
+
+	if asyncMode {
+		results := enqueueItems(items)
+		return c.JSON(http.StatusAccepted, BatchRecordResponse{Results: results})
+	}
+
+	dynamoStore, ok := store.(*DynamoStore)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotImplemented, "batch writes require STORAGE_BACKEND=dynamo")
+	}
+
+	results, err := putBatchWithRetry(c.Request().Context(), dynamoStore.client, dynamoStore.tableName, items)
+	if err != nil {
+		log.Errorf("putBatchWithRetry Error: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, BatchRecordResponse{Results: results})
+}
+
+// putBatchWithRetry writes items to tableName via BatchWriteItem, chunking
+// into groups of dynamoBatchLimit and retrying only the UnprocessedItems
+// the SDK hands back, with exponential backoff between attempts.
+func putBatchWithRetry(ctx context.Context, svc *dynamodb.DynamoDB, tableName string, items []NlpText) ([]BatchItemResult, error) {
+	results := make(map[string]*BatchItemResult, len(items))
+	for _, item := range items {
+		results[item.Hash] = &BatchItemResult{Hash: item.Hash, Status: "written"}
+	}
+
+	for start := 0; start < len(items); start += dynamoBatchLimit {
+		end := start + dynamoBatchLimit
+		if end > len(items) {
+			end = len(items)
+		}
+		chunk := items[start:end]
+
+		writeRequests, err := toWriteRequests(chunk)
+		if err != nil {
+			return nil, err
+		}
+
+		unprocessed := map[string][]*dynamodb.WriteRequest{tableName: writeRequests}
+		attempt := 0
+		for len(unprocessed) > 0 {
+			out, err := svc.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{
+				RequestItems: unprocessed,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			unprocessed = out.UnprocessedItems
+			if len(unprocessed) == 0 {
+				break
+			}
+
+			attempt++
+			atomic.AddInt64(&queueRetries, 1)
+			if attempt >= batchMaxAttempts {
+				markFailed(results, unprocessed, tableName, "exceeded max retry attempts")
+				break
+			}
+			time.Sleep(backoffDelay(attempt))
+		}
+	}
+
+	ordered := make([]BatchItemResult, 0, len(items))
+	for _, item := range items {
+		ordered = append(ordered, *results[item.Hash])
+	}
+	return ordered, nil
+}
+
+// backoffDelay returns the delay before retry attempt n (1-indexed),
+// starting at backoffBase, doubling each attempt, capped at backoffCap,
+// with a small jitter to avoid synchronized retries.
+func backoffDelay(attempt int) time.Duration {
+	delay := backoffBase << uint(attempt-1)
+	if delay > backoffCap || delay <= 0 {
+		delay = backoffCap
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay) / 4))
+	return delay + jitter
+}
+
+// toWriteRequests builds one WriteRequest per distinct Hash in items.
+// BatchWriteItem rejects a request containing two entries for the same
+// key, so items sharing a hash (identical text) are merged into a single
+// PutRequest; putBatchWithRetry's results map is already keyed by hash, so
+// every item with that hash still gets its status in the response.
+func toWriteRequests(items []NlpText) ([]*dynamodb.WriteRequest, error) {
+	seen := make(map[string]bool, len(items))
+	requests := make([]*dynamodb.WriteRequest, 0, len(items))
+	for _, item := range items {
+		if seen[item.Hash] {
+			continue
+		}
+		seen[item.Hash] = true
+
+		av, err := dynamodbattribute.MarshalMap(item)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, &dynamodb.WriteRequest{
+			PutRequest: &dynamodb.PutRequest{Item: av},
+		})
+	}
+	return requests, nil
+}
+
+// markFailed flags items left in unprocessed (keyed by their marshaled Hash
+// attribute) as permanently failed in results.
+func markFailed(results map[string]*BatchItemResult, unprocessed map[string][]*dynamodb.WriteRequest, tableName, reason string) {
+	for _, req := range unprocessed[tableName] {
+		if req.PutRequest == nil {
+			continue
+		}
+		if hashAttr, ok := req.PutRequest.Item["Hash"]; ok && hashAttr.S != nil {
+			if result, ok := results[*hashAttr.S]; ok {
+				result.Status = "failed"
+				result.Error = reason
+			}
+		}
+	}
+}
+
+// --- async/SQS ingestion path -------------------------------------------
+
+var sqsClient *sqs.SQS
+
+func enqueueItems(items []NlpText) []BatchItemResult {
+	sess := session.Must(session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	}))
+	if sqsClient == nil {
+		sqsClient = sqs.New(sess)
+	}
+
+	results := make([]BatchItemResult, 0, len(items))
+	for _, item := range items {
+		body, err := json.Marshal(item)
+		if err != nil {
+			results = append(results, BatchItemResult{Hash: item.Hash, Status: "failed", Error: err.Error()})
+			continue
+		}
+
+		_, err = sqsClient.SendMessage(&sqs.SendMessageInput{
+			QueueUrl:    aws.String(sqsQueueURL),
+			MessageBody: aws.String(string(body)),
+		})
+		if err != nil {
+			log.Errorf("sqsClient.SendMessage Error: %v", err)
+			results = append(results, BatchItemResult{Hash: item.Hash, Status: "failed", Error: err.Error()})
+			continue
+		}
+
+		atomic.AddInt64(&queueDepth, 1)
+		results = append(results, BatchItemResult{Hash: item.Hash, Status: "queued"})
+	}
+	return results
+}
+
+// workerWG and workerStopCh let runUntilSignal drain the worker pool
+// before the process exits, instead of killing workers mid-write.
+// workerStopCh is nil until startWorkerPool actually launches workers.
+var (
+	workerWG     sync.WaitGroup
+	workerStopCh chan struct{}
+)
+
+// startWorkerPool launches workerCount goroutines that poll sqsQueueURL,
+// write each item to DynamoDB, and forward permanently-failed items to
+// sqsDLQURL. It is a no-op unless ASYNC_MODE is enabled.
+func startWorkerPool() {
+	if !asyncMode || sqsQueueURL == "" {
+		return
+	}
+
+	dynamoStore, ok := store.(*DynamoStore)
+	if !ok {
+		log.Errorf("startWorkerPool: ASYNC_MODE requires STORAGE_BACKEND=dynamo")
+		return
+	}
+
+	if workerCount <= 0 {
+		log.Errorf("startWorkerPool: WORKER_COUNT=%d, nothing will drain %s; set WORKER_COUNT to a positive integer", workerCount, sqsQueueURL)
+		return
+	}
+
+	sess := session.Must(session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	}))
+	sqsClient = sqs.New(sess)
+	workerStopCh = make(chan struct{})
+
+	for i := 0; i < workerCount; i++ {
+		workerWG.Add(1)
+		go func(workerID int) {
+			defer workerWG.Done()
+			runWorker(workerID, dynamoStore.client, sqsClient, workerStopCh)
+		}(i)
+	}
+}
+
+// stopWorkerPool signals every worker to finish its current batch and
+// return, then blocks until they do or ctx is done, whichever comes
+// first. It is a no-op if the worker pool was never started.
+func stopWorkerPool(ctx context.Context) error {
+	if workerStopCh == nil {
+		return nil
+	}
+	close(workerStopCh)
+
+	done := make(chan struct{})
+	go func() {
+		workerWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func runWorker(workerID int, svc *dynamodb.DynamoDB, client *sqs.SQS, stopCh <-chan struct{}) {
+	for {
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
+
+		out, err := client.ReceiveMessage(&sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(sqsQueueURL),
+			MaxNumberOfMessages: aws.Int64(10),
+			WaitTimeSeconds:     aws.Int64(10),
+		})
+		if err != nil {
+			log.Errorf("worker %d ReceiveMessage Error: %v", workerID, err)
+			time.Sleep(backoffBase)
+			continue
+		}
+
+		for _, msg := range out.Messages {
+			processMessage(svc, client, msg, stopCh)
+		}
+	}
+}
+
+// processMessage writes msg to DynamoDB, retrying with backoff until it
+// succeeds, exhausts batchMaxAttempts, or stopCh fires. In the stopCh case the
+// message is left undeleted so SQS redelivers it (to this or another worker)
+// once its visibility timeout elapses, rather than holding up shutdown for
+// the remainder of the backoff.
+func processMessage(svc *dynamodb.DynamoDB, client *sqs.SQS, msg *sqs.Message, stopCh <-chan struct{}) {
+	var item NlpText
+	if err := json.Unmarshal([]byte(*msg.Body), &item); err != nil {
+		log.Errorf("worker Unmarshal Error: %v", err)
+		sendToDLQ(client, *msg.Body)
+		deleteMessage(client, msg)
+		return
+	}
+
+	attempt := 0
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultRequestTimeout)
+		av, err := dynamodbattribute.MarshalMap(item)
+		if err == nil {
+			_, err = svc.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+				Item:      av,
+				TableName: aws.String(dynamoTableName),
+			})
+		}
+		cancel()
+		if err == nil {
+			atomic.AddInt64(&queueDepth, -1)
+			deleteMessage(client, msg)
+			return
+		}
+
+		attempt++
+		atomic.AddInt64(&queueRetries, 1)
+		if attempt >= batchMaxAttempts {
+			log.Errorf("worker giving up on hash %s after %d attempts: %v", item.Hash, attempt, err)
+			sendToDLQ(client, *msg.Body)
+			atomic.AddInt64(&queueDepth, -1)
+			deleteMessage(client, msg)
+			return
+		}
+
+		select {
+		case <-stopCh:
+			log.Errorf("worker abandoning hash %s mid-retry for shutdown; message will be redelivered", item.Hash)
+			return
+		case <-time.After(backoffDelay(attempt)):
+		}
+	}
+}
+
+func sendToDLQ(client *sqs.SQS, body string) {
+	if sqsDLQURL == "" {
+		return
+	}
+	_, err := client.SendMessage(&sqs.SendMessageInput{
+		QueueUrl:    aws.String(sqsDLQURL),
+		MessageBody: aws.String(body),
+	})
+	if err != nil {
+		log.Errorf("sendToDLQ Error: %v", err)
+		return
+	}
+	atomic.AddInt64(&dlqSize, 1)
+}
+
+func deleteMessage(client *sqs.SQS, msg *sqs.Message) {
+	_, err := client.DeleteMessage(&sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(sqsQueueURL),
+		ReceiptHandle: msg.ReceiptHandle,
+	})
+	if err != nil {
+		log.Errorf("deleteMessage Error: %v", err)
+	}
+}
+
+// --- queue metrics --------------------------------------------------------
+
+func getQueueMetrics(c echo.Context) error {
+	metrics := struct {
+		InFlight int64 `json:"in_flight"`
+		Retries  int64 `json:"retries"`
+		DLQSize  int64 `json:"dlq_size"`
+	}{
+		InFlight: atomic.LoadInt64(&queueDepth),
+		Retries:  atomic.LoadInt64(&queueRetries),
+		DLQSize:  atomic.LoadInt64(&dlqSize),
+	}
+	return c.JSON(http.StatusOK, metrics)
+}
@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt int
+	}{
+		{"first attempt", 1},
+		{"second attempt", 2},
+		{"large attempt capped", 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay := backoffDelay(tt.attempt)
+			if delay < backoffBase {
+				t.Errorf("backoffDelay(%d) = %v, want >= backoffBase (%v)", tt.attempt, delay, backoffBase)
+			}
+			if delay > backoffCap+backoffCap/4 {
+				t.Errorf("backoffDelay(%d) = %v, want <= backoffCap plus jitter (%v)", tt.attempt, delay, backoffCap)
+			}
+		})
+	}
+}
+
+func TestBackoffDelayGrowsWithAttempt(t *testing.T) {
+	// Compare the non-jittered floor of each delay, since jitter alone
+	// could otherwise make a later attempt look smaller than an earlier one.
+	first := backoffBase << uint(1-1)
+	second := backoffBase << uint(2-1)
+	if second <= first {
+		t.Fatalf("expected backoff to double between attempts 1 and 2, got %v then %v", first, second)
+	}
+}
+
+func TestToWriteRequestsDedupesByHash(t *testing.T) {
+	items := []NlpText{
+		{Hash: "h1", Text: "a", Timestamp: 1},
+		{Hash: "h1", Text: "a", Timestamp: 2},
+		{Hash: "h2", Text: "b", Timestamp: 3},
+	}
+
+	requests, err := toWriteRequests(items)
+	if err != nil {
+		t.Fatalf("toWriteRequests() error = %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("toWriteRequests() returned %d requests, want 2 (one per distinct hash)", len(requests))
+	}
+}
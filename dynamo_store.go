@@ -0,0 +1,168 @@
+// author: Gary A. Stafford
+// purpose: DynamoDB-backed implementation of the Store interface. The
+//          session and client are created once in newDynamoStore and
+//          reused across requests instead of per-request.
+// modified: 2021-06-25
+
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const dynamoTableName = "NLPText"
+
+type DynamoStore struct {
+	client    *dynamodb.DynamoDB
+	tableName string
+}
+
+// newDynamoStore initializes a session that the SDK will use to load
+// credentials from the shared credentials file ~/.aws/credentials and
+// region from the shared configuration file ~/.aws/config, once, and
+// builds the client the store reuses for every request.
+func newDynamoStore() *DynamoStore {
+	sess := session.Must(session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	}))
+
+	return &DynamoStore{
+		client:    dynamodb.New(sess),
+		tableName: dynamoTableName,
+	}
+}
+
+func (s *DynamoStore) Put(ctx context.Context, item NlpText) error {
+	ctx, span := tracer.Start(ctx, "DynamoStore.Put", trace.WithAttributes(
+		attribute.String("table_name", s.tableName),
+		attribute.String("hash", item.Hash),
+		attribute.Int("text_length", len(item.Text)),
+	))
+	defer span.End()
+
+	timer := prometheus.NewTimer(putItemDuration)
+	defer timer.ObserveDuration()
+
+	av, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	_, err = s.client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(s.tableName),
+	})
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	recordsTotal.Inc()
+	return nil
+}
+
+func (s *DynamoStore) Get(ctx context.Context, hash string) (*NlpText, error) {
+	ctx, span := tracer.Start(ctx, "DynamoStore.Get", trace.WithAttributes(
+		attribute.String("table_name", s.tableName),
+		attribute.String("hash", hash),
+	))
+	defer span.End()
+
+	out, err := s.client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"Hash": {S: aws.String(hash)},
+		},
+	})
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if out.Item == nil {
+		return nil, nil
+	}
+
+	var item NlpText
+	if err := dynamodbattribute.UnmarshalMap(out.Item, &item); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (s *DynamoStore) List(ctx context.Context, opts ListOptions) (ListResult, error) {
+	ctx, span := tracer.Start(ctx, "DynamoStore.List", trace.WithAttributes(
+		attribute.String("table_name", s.tableName),
+	))
+	defer span.End()
+
+	startKey, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		return ListResult{}, err
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	scanInput := &dynamodb.ScanInput{
+		TableName:         aws.String(s.tableName),
+		Limit:             aws.Int64(int64(limit)),
+		ExclusiveStartKey: startKey,
+	}
+
+	if filter, ok := timeRangeFilter(opts); ok {
+		expr, err := expression.NewBuilder().WithFilter(filter).Build()
+		if err != nil {
+			return ListResult{}, err
+		}
+		scanInput.FilterExpression = expr.Filter()
+		scanInput.ExpressionAttributeNames = expr.Names()
+		scanInput.ExpressionAttributeValues = expr.Values()
+	}
+
+	out, err := s.client.ScanWithContext(ctx, scanInput)
+	if err != nil {
+		return ListResult{}, err
+	}
+
+	items := make([]NlpText, 0, len(out.Items))
+	if err := dynamodbattribute.UnmarshalListOfMaps(out.Items, &items); err != nil {
+		return ListResult{}, err
+	}
+
+	cursor, err := encodeCursor(out.LastEvaluatedKey)
+	if err != nil {
+		return ListResult{}, err
+	}
+
+	return ListResult{Items: items, Cursor: cursor}, nil
+}
+
+// timeRangeFilter builds a Timestamp filter from opts.From/opts.To,
+// treating each bound independently: a zero value leaves that side
+// unbounded rather than requiring both to be set. Returns ok=false if
+// neither bound is set, meaning no filter should be applied.
+func timeRangeFilter(opts ListOptions) (expression.ConditionBuilder, bool) {
+	switch {
+	case opts.From != 0 && opts.To != 0:
+		return expression.Between(expression.Name("Timestamp"), expression.Value(opts.From), expression.Value(opts.To)), true
+	case opts.From != 0:
+		return expression.GreaterThanEqual(expression.Name("Timestamp"), expression.Value(opts.From)), true
+	case opts.To != 0:
+		return expression.LessThanEqual(expression.Name("Timestamp"), expression.Value(opts.To)), true
+	default:
+		return expression.ConditionBuilder{}, false
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestTimeRangeFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   ListOptions
+		wantOK bool
+	}{
+		{"neither bound set", ListOptions{}, false},
+		{"from only", ListOptions{From: 10}, true},
+		{"to only", ListOptions{To: 20}, true},
+		{"both bounds set", ListOptions{From: 10, To: 20}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := timeRangeFilter(tt.opts)
+			if ok != tt.wantOK {
+				t.Errorf("timeRangeFilter(%+v) ok = %v, want %v", tt.opts, ok, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// author: Gary A. Stafford
+// purpose: Request-scoped deadlines for downstream AWS calls, and a
+//          graceful shutdown path so in-flight writes aren't dropped when
+//          the process receives SIGTERM/SIGINT.
+// modified: 2021-07-18
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+var (
+	defaultRequestTimeout = getEnvDuration("REQUEST_TIMEOUT", 5*time.Second)
+	maxRequestTimeout     = getEnvDuration("MAX_REQUEST_TIMEOUT", 30*time.Second)
+	shutdownGracePeriod   = getEnvDuration("SHUTDOWN_GRACE_PERIOD", 10*time.Second)
+	serverReadTimeout     = getEnvDuration("SERVER_READ_TIMEOUT", 5*time.Second)
+	serverWriteTimeout    = getEnvDuration("SERVER_WRITE_TIMEOUT", 10*time.Second)
+)
+
+// getEnvDuration parses key as a Go duration string (e.g. "5s"); an unset
+// or unparseable value falls back to fallback.
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value, ok := os.LookupEnv(key); ok {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
+
+// requestDeadline bounds the request's context so a slow DynamoDB response
+// can't pile up goroutines indefinitely. Callers may override the default
+// via the X-Request-Timeout header (a Go duration string, e.g. "2s"), capped
+// at maxRequestTimeout so a caller can't use the header to defeat the bound.
+func requestDeadline(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		timeout := defaultRequestTimeout
+		if raw := c.Request().Header.Get("X-Request-Timeout"); raw != "" {
+			if d, err := time.ParseDuration(raw); err == nil && d > 0 && d <= maxRequestTimeout {
+				timeout = d
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+		defer cancel()
+		c.SetRequest(c.Request().WithContext(ctx))
+
+		return next(c)
+	}
+}
+
+// runUntilSignal starts server and the SQS worker pool (if enabled) in the
+// background and blocks until a SIGINT/SIGTERM arrives, then shuts both
+// down, giving in-flight requests and in-flight batch writes up to
+// shutdownGracePeriod to finish.
+func runUntilSignal(server *http.Server) error {
+	go func() {
+		if err := e.StartServer(server); err != nil && err != http.ErrServerClosed {
+			log.Errorf("e.StartServer Error: %v", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+	defer cancel()
+
+	var shutdownErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		shutdownErr = e.Shutdown(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		if err := stopWorkerPool(ctx); err != nil {
+			log.Errorf("stopWorkerPool Error: %v", err)
+		}
+	}()
+	wg.Wait()
+
+	return shutdownErr
+}
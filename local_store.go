@@ -0,0 +1,119 @@
+// author: Gary A. Stafford
+// purpose: Embedded key-value implementation of the Store interface, so
+//          the service can run without AWS credentials for local dev, CI,
+//          and tests. Selected via STORAGE_BACKEND=local.
+// modified: 2021-06-25
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var recordsBucket = []byte("records")
+
+type LocalStore struct {
+	db *bolt.DB
+}
+
+// newLocalStore opens (creating if necessary) a bbolt database at path and
+// ensures the records bucket exists.
+func newLocalStore(path string) (*LocalStore, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(recordsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalStore{db: db}, nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, item NlpText) error {
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(recordsBucket).Put([]byte(item.Hash), raw)
+	})
+}
+
+func (s *LocalStore) Get(ctx context.Context, hash string) (*NlpText, error) {
+	var item *NlpText
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(recordsBucket).Get([]byte(hash))
+		if raw == nil {
+			return nil
+		}
+		var found NlpText
+		if err := json.Unmarshal(raw, &found); err != nil {
+			return err
+		}
+		item = &found
+		return nil
+	})
+
+	return item, err
+}
+
+// List scans the bucket in key order, applying the time range filter and
+// picking up after opts.Cursor (the last hash returned on the prior page).
+func (s *LocalStore) List(ctx context.Context, opts ListOptions) (ListResult, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	var matched []NlpText
+	var nextCursor string
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(recordsBucket).Cursor()
+
+		var k, v []byte
+		if opts.Cursor != "" {
+			c.Seek([]byte(opts.Cursor))
+			k, v = c.Next() // resume after the cursor key, not on it
+		} else {
+			k, v = c.First()
+		}
+
+		for ; k != nil; k, v = c.Next() {
+			var item NlpText
+			if err := json.Unmarshal(v, &item); err != nil {
+				return err
+			}
+
+			if opts.From != 0 && item.Timestamp < opts.From {
+				continue
+			}
+			if opts.To != 0 && item.Timestamp > opts.To {
+				continue
+			}
+
+			matched = append(matched, item)
+			if len(matched) == limit {
+				nextCursor = string(k)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return ListResult{}, err
+	}
+
+	return ListResult{Items: matched, Cursor: nextCursor}, nil
+}
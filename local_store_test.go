@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func newTestLocalStore(t *testing.T) *LocalStore {
+	t.Helper()
+
+	store, err := newLocalStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("newLocalStore() error = %v", err)
+	}
+	return store
+}
+
+func TestLocalStorePutGet(t *testing.T) {
+	store := newTestLocalStore(t)
+	ctx := context.Background()
+
+	item := NlpText{Hash: "abc123", Text: "hello", Timestamp: 100}
+	if err := store.Put(ctx, item); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	got, err := store.Get(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got == nil || *got != item {
+		t.Fatalf("Get() = %+v, want %+v", got, item)
+	}
+}
+
+func TestLocalStoreGetMissing(t *testing.T) {
+	store := newTestLocalStore(t)
+
+	got, err := store.Get(context.Background(), "does-not-exist")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got != nil {
+		t.Fatalf("Get() = %+v, want nil", got)
+	}
+}
+
+func TestLocalStoreListPagination(t *testing.T) {
+	store := newTestLocalStore(t)
+	ctx := context.Background()
+
+	const total = 5
+	for i := 0; i < total; i++ {
+		item := NlpText{Hash: fmt.Sprintf("hash-%d", i), Text: "x", Timestamp: int64(i)}
+		if err := store.Put(ctx, item); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > total {
+			t.Fatalf("List() did not terminate after %d pages", pages)
+		}
+
+		result, err := store.List(ctx, ListOptions{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		for _, item := range result.Items {
+			if seen[item.Hash] {
+				t.Fatalf("List() returned duplicate hash %q across pages", item.Hash)
+			}
+			seen[item.Hash] = true
+		}
+		if result.Cursor == "" {
+			break
+		}
+		cursor = result.Cursor
+	}
+
+	if len(seen) != total {
+		t.Fatalf("List() across pages returned %d items, want %d", len(seen), total)
+	}
+}
+
+func TestLocalStoreListTimeRange(t *testing.T) {
+	store := newTestLocalStore(t)
+	ctx := context.Background()
+
+	for i, ts := range []int64{10, 20, 30} {
+		item := NlpText{Hash: fmt.Sprintf("hash-%d", i), Text: "x", Timestamp: ts}
+		if err := store.Put(ctx, item); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want int
+	}{
+		{"unbounded", ListOptions{}, 3},
+		{"from only", ListOptions{From: 15}, 2},
+		{"to only", ListOptions{To: 25}, 2},
+		{"from and to", ListOptions{From: 15, To: 25}, 1},
+		{"excludes everything", ListOptions{From: 100}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := store.List(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(result.Items) != tt.want {
+				t.Errorf("List(%+v) returned %d items, want %d", tt.opts, len(result.Items), tt.want)
+			}
+		})
+	}
+}
@@ -9,28 +9,24 @@
 package main
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
-	"encoding/json"
 	"net/http"
 	"os"
 	"strconv"
-	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
 )
 
 type NlpText struct {
-	Timestamp int64  `json:"timestamp"` // record date/time
-	Hash      string `json:"hash"`      // MD5 hash of text
-	Text      string `json:"text"`      // The text in the request
+	Timestamp int64  `json:"timestamp" xml:"timestamp"` // record date/time
+	Hash      string `json:"hash" xml:"hash"`           // MD5 hash of text
+	Text      string `json:"text" xml:"text"`           // The text in the request
 }
 
 var (
@@ -38,6 +34,7 @@ var (
 	serverPort = getEnv("DYNAMO_PORT", ":8080")
 	apiKey     = getEnv("API_KEY", "ChangeMe")
 	e          = echo.New()
+	store      Store
 )
 
 func getEnv(key, fallback string) string {
@@ -62,80 +59,81 @@ func getMD5Hash(text string) string {
 }
 
 func writeToDynamo(c echo.Context) error {
-	//Initialize a session that the SDK will use to load
-	//credentials from the shared credentials file ~/.aws/credentials
-	//and region from the shared configuration file ~/.aws/config.
-	sess := session.Must(session.NewSessionWithOptions(session.Options{
-		SharedConfigState: session.SharedConfigEnable,
-	}))
-
-	// Create DynamoDB client
-	svc := dynamodb.New(sess)
-
-	tableName := "NLPText"
-
-	var nlpText NlpText
-	jsonMap := make(map[string]interface{})
-	err := json.NewDecoder(c.Request().Body).Decode(&jsonMap)
-	if err != nil {
-		log.Errorf("json.NewDecoder Error: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	var req RecordRequest
+	if err := c.Bind(&req); err != nil {
+		log.Errorf("c.Bind Error: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
 	}
 
-	text := (jsonMap["text"]).(string)
-	nlpText.Hash = getMD5Hash(text)
+	text := req.Text
 	// truncate long text inputs
 	if len(text) > 1000 {
 		text = text[0:1000] + "..."
 	}
-	nlpText.Timestamp = time.Now().Unix()
-	nlpText.Text = text
-
-	av, err := dynamodbattribute.MarshalMap(nlpText)
-	if err != nil {
-		log.Errorf("dynamodbattribute.MarshalMap Error: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, err)
-	}
 
-	input := &dynamodb.PutItemInput{
-		Item:      av,
-		TableName: aws.String(tableName),
+	nlpText := NlpText{
+		Hash:      getMD5Hash(text),
+		Text:      text,
+		Timestamp: time.Now().Unix(),
 	}
 
-	_, err = svc.PutItem(input)
-	if err != nil {
-		log.Errorf("svc.PutItem Error: %v", err)
+	if err := store.Put(c.Request().Context(), nlpText); err != nil {
+		log.Errorf("store.Put Error: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
-	return c.JSON(http.StatusOK, nil)
+	return respond(c, http.StatusOK, nlpText)
 }
 
 func run() error {
+	var err error
+	store, err = newStore()
+	if err != nil {
+		return err
+	}
+
+	shutdownTracing, err := initTracing(context.Background())
+	if err != nil {
+		return err
+	}
+	defer shutdownTracing(context.Background())
+
 	// Middleware
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
+	e.Use(middleware.BodyLimit(maxBodyBytes))
+	e.Use(requestDeadline)
+	e.Use(trackRequestMetrics)
+	e.Validator = &CustomValidator{validate: validator.New()}
 
-	e.Use(middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{
-		KeyLookup: "header:X-API-Key",
-		Skipper: func(c echo.Context) bool {
-			if strings.HasPrefix(c.Request().RequestURI, "/health") {
-				return true
-			}
-			return false
-		},
-		Validator: func(key string, c echo.Context) (bool, error) {
-			log.Debugf("API_KEY: %v", apiKey)
-			return key == apiKey, nil
-		},
-	}))
-
-	// Routes
+	// Public routes: no auth required
 	e.GET("/health", getHealth)
-	e.POST("/record", writeToDynamo)
-
-	// Start server
-	return e.Start(serverPort)
+	e.GET("/metrics", getMetrics)
+	e.POST("/login", postLogin)
+
+	// Authenticated routes: X-API-Key or JWT bearer token, rate limited
+	// per authenticated subject
+	api := e.Group("", authMiddleware, rateLimitBySubject())
+	api.POST("/record", writeToDynamo, requireRole(roleWriter))
+	api.POST("/records/batch", postBatchRecords, requireRole(roleWriter))
+	api.GET("/metrics/queue", getQueueMetrics, requireRole(roleReader))
+	api.GET("/records/:hash", getRecord, requireRole(roleReader))
+	api.GET("/records", listRecords, requireRole(roleReader))
+
+	// Background ingestion workers, no-op unless ASYNC_MODE is enabled
+	startWorkerPool()
+
+	// Start server with read/write timeouts, and shut down gracefully on
+	// SIGINT/SIGTERM instead of dropping in-flight requests
+	server := &http.Server{
+		Addr:         serverPort,
+		ReadTimeout:  serverReadTimeout,
+		WriteTimeout: serverWriteTimeout,
+	}
+	return runUntilSignal(server)
 }
 
 func init() {
@@ -0,0 +1,59 @@
+// author: Gary A. Stafford
+// purpose: Prometheus metrics for the service: total records written,
+//          HTTP requests by status code, DynamoDB PutItem latency, and
+//          in-flight request count.
+// modified: 2021-08-02
+
+package main
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	recordsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "dynamo_app_records_total",
+		Help: "Total number of records written.",
+	})
+
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dynamo_app_http_requests_total",
+		Help: "HTTP requests by status code.",
+	}, []string{"status"})
+
+	putItemDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "dynamo_app_put_item_duration_seconds",
+		Help:    "DynamoDB PutItem call latency, in seconds.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	activeRequests = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "dynamo_app_active_requests",
+		Help: "Number of HTTP requests currently being handled.",
+	})
+)
+
+// getMetrics serves the Prometheus exposition format. It bypasses the auth
+// middleware the same way /health does.
+func getMetrics(c echo.Context) error {
+	promhttp.Handler().ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+// trackRequestMetrics updates activeRequests and httpRequestsTotal around
+// every request, regardless of route.
+func trackRequestMetrics(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		activeRequests.Inc()
+		defer activeRequests.Dec()
+
+		err := next(c)
+		httpRequestsTotal.WithLabelValues(strconv.Itoa(c.Response().Status)).Inc()
+		return err
+	}
+}
@@ -0,0 +1,113 @@
+// author: Gary A. Stafford
+// purpose: Read-side handlers for NLPText records: single-item fetch by
+//          hash, and a paginated, time-range-filtered list. Delegates to
+//          whichever Store backend is configured.
+// modified: 2021-06-25
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const defaultListLimit = 25
+
+// RecordListResponse is the response envelope for GET /records, carrying an
+// opaque cursor clients pass back as ?cursor= to fetch the next page.
+type RecordListResponse struct {
+	Items  []NlpText `json:"items"`
+	Cursor string    `json:"cursor,omitempty"`
+}
+
+func getRecord(c echo.Context) error {
+	hash := c.Param("hash")
+
+	record, err := store.Get(c.Request().Context(), hash)
+	if err != nil {
+		log.Errorf("store.Get Error: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if record == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "record not found")
+	}
+
+	return c.JSON(http.StatusOK, record)
+}
+
+func listRecords(c echo.Context) error {
+	opts := ListOptions{Cursor: c.QueryParam("cursor")}
+
+	if raw := c.QueryParam("limit"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			opts.Limit = n
+		}
+	}
+	opts.From, opts.To = parseTimeRange(c)
+
+	result, err := store.List(c.Request().Context(), opts)
+	if err != nil {
+		log.Errorf("store.List Error: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, RecordListResponse{Items: result.Items, Cursor: result.Cursor})
+}
+
+// parseTimeRange reads ?from= and ?to= (unix seconds) from the request.
+// Each is independent and optional, matching ListOptions: an absent or
+// unparseable value leaves that side at 0 (unbounded), the other bound
+// still applies.
+func parseTimeRange(c echo.Context) (from, to int64) {
+	if raw := c.QueryParam("from"); raw != "" {
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			from = n
+		}
+	}
+	if raw := c.QueryParam("to"); raw != "" {
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			to = n
+		}
+	}
+	return from, to
+}
+
+// decodeCursor turns an opaque ?cursor= value back into a DynamoDB
+// LastEvaluatedKey attribute map, for the DynamoStore. An empty cursor
+// means "start from the beginning".
+func decodeCursor(cursor string) (map[string]*dynamodb.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.URLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var key map[string]*dynamodb.AttributeValue
+	if err := json.Unmarshal(raw, &key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// encodeCursor turns a DynamoDB LastEvaluatedKey into the opaque cursor
+// value returned to clients. An empty/nil key means there is no next page.
+func encodeCursor(key map[string]*dynamodb.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+
+	raw, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(raw), nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func parseTimeRangeRequest(t *testing.T, target string) (from, to int64) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+
+	return parseTimeRange(c)
+}
+
+func TestParseTimeRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		wantFrom int64
+		wantTo   int64
+	}{
+		{"neither param", "/records", 0, 0},
+		{"from only", "/records?from=100", 100, 0},
+		{"to only", "/records?to=200", 0, 200},
+		{"both params", "/records?from=100&to=200", 100, 200},
+		{"invalid from ignored", "/records?from=nope&to=200", 0, 200},
+		{"invalid to ignored", "/records?from=100&to=nope", 100, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := parseTimeRangeRequest(t, tt.target)
+			if from != tt.wantFrom || to != tt.wantTo {
+				t.Errorf("parseTimeRange(%q) = (%d, %d), want (%d, %d)", tt.target, from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// author: Gary A. Stafford
+// purpose: Storage abstraction for NLPText records, so the service can run
+//          against DynamoDB in production or an embedded local store for
+//          tests and offline development.
+// modified: 2021-06-25
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// ListOptions filters and paginates a List call. From/To are unix seconds;
+// each is unbounded on its own side when left zero (From: no lower bound,
+// To: no upper bound), independently of the other. Cursor is the opaque
+// value returned as ListResult.Cursor from a previous call.
+type ListOptions struct {
+	From, To int64
+	Limit    int
+	Cursor   string
+}
+
+// ListResult carries a page of records plus an opaque cursor for the next
+// page. Cursor is empty when there are no more records.
+type ListResult struct {
+	Items  []NlpText
+	Cursor string
+}
+
+// Store is the persistence interface writeToDynamo and the read handlers
+// are written against, so the backend can be swapped via STORAGE_BACKEND
+// without touching handler code.
+type Store interface {
+	Put(ctx context.Context, item NlpText) error
+	Get(ctx context.Context, hash string) (*NlpText, error)
+	List(ctx context.Context, opts ListOptions) (ListResult, error)
+}
+
+var storageBackend = getEnv("STORAGE_BACKEND", "dynamo")
+
+// newStore constructs the Store selected by STORAGE_BACKEND. It is called
+// once at startup; the returned Store holds any long-lived client/handle so
+// handlers don't pay per-request connection setup.
+func newStore() (Store, error) {
+	switch storageBackend {
+	case "dynamo":
+		return newDynamoStore(), nil
+	case "local":
+		return newLocalStore(getEnv("LOCAL_STORE_PATH", "dynamo-app.db"))
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q, want \"dynamo\" or \"local\"", storageBackend)
+	}
+}
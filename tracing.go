@@ -0,0 +1,55 @@
+// author: Gary A. Stafford
+// purpose: OpenTelemetry tracing around DynamoDB calls, exported via OTLP.
+//          Disabled unless OTEL_EXPORTER_OTLP_ENDPOINT is set.
+// modified: 2021-08-02
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+var (
+	otelExporterEndpoint = getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	tracer               = otel.Tracer("dynamo-app")
+)
+
+// initTracing wires a BatchSpanProcessor backed by an OTLP/gRPC exporter
+// into the global TracerProvider. It returns a shutdown func to flush and
+// close the exporter; when OTEL_EXPORTER_OTLP_ENDPOINT is unset, tracing
+// is a no-op and the shutdown func does nothing.
+func initTracing(ctx context.Context) (func(context.Context) error, error) {
+	if otelExporterEndpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracegrpc.New(ctx,
+		otlptracegrpc.WithEndpoint(otelExporterEndpoint),
+		otlptracegrpc.WithInsecure(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("otlptracegrpc.New: %w", err)
+	}
+
+	res, err := resource.New(ctx, resource.WithAttributes(
+		semconv.ServiceNameKey.String("dynamo-app"),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("resource.New: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
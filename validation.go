@@ -0,0 +1,48 @@
+// author: Gary A. Stafford
+// purpose: Typed request binding and validation for /record, replacing the
+//          hand-rolled map decode that panicked on missing/non-string
+//          input. Supports JSON and XML request bodies and negotiates the
+//          response representation from Accept.
+// modified: 2021-07-10
+
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+// maxBodyBytes bounds request bodies accepted by the server; oversized
+// bodies are rejected before being read into memory.
+var maxBodyBytes = getEnv("MAX_BODY_SIZE", "1M")
+
+// RecordRequest is the typed payload for POST /record, bound from either a
+// JSON or XML body via echo's default Bind.
+type RecordRequest struct {
+	Text string `json:"text" xml:"text" form:"text" validate:"required,min=1,max=100000"`
+}
+
+// CustomValidator adapts go-playground/validator to echo.Context.Validate.
+type CustomValidator struct {
+	validate *validator.Validate
+}
+
+func (cv *CustomValidator) Validate(i interface{}) error {
+	if err := cv.validate.Struct(i); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return nil
+}
+
+// respond writes data using the representation requested in Accept,
+// defaulting to JSON when the client expresses no preference or asks for
+// something other than XML.
+func respond(c echo.Context, status int, data interface{}) error {
+	if strings.Contains(c.Request().Header.Get("Accept"), "xml") {
+		return c.XML(status, data)
+	}
+	return c.JSON(status, data)
+}